@@ -0,0 +1,67 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import "bytes"
+
+// unreservedTable and reservedTable are 256-entry lookup tables marking
+// which bytes may be written through unescaped in each expansion mode.
+// They replace the regexp.ReplaceAllFunc scan the package used previously,
+// which re-ran a regular expression, converted every value to and from
+// []byte, and allocated a fresh three-byte slice for each byte that needed
+// encoding.
+var (
+	unreservedTable [256]bool
+	reservedTable   [256]bool
+)
+
+func init() {
+	for b := 'A'; b <= 'Z'; b++ {
+		unreservedTable[b] = true
+	}
+	for b := 'a'; b <= 'z'; b++ {
+		unreservedTable[b] = true
+	}
+	for b := '0'; b <= '9'; b++ {
+		unreservedTable[b] = true
+	}
+	for _, b := range []byte("-._~") {
+		unreservedTable[b] = true
+	}
+	reservedTable = unreservedTable
+	for _, b := range []byte(":/?#[]@!$&'()*+,;=") {
+		reservedTable[b] = true
+	}
+}
+
+var hexDigits = []byte("0123456789ABCDEF")
+
+// escapeTo writes the percent-encoded form of s to buf, consulting
+// reservedTable when allowReserved is true and unreservedTable otherwise.
+// Runs of bytes that need no encoding are written with a single
+// WriteString call; only the bytes outside the allowed set are expanded to
+// their %XX form.
+func escapeTo(buf *bytes.Buffer, s string, allowReserved bool) {
+	table := &unreservedTable
+	if allowReserved {
+		table = &reservedTable
+	}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if table[s[i]] {
+			continue
+		}
+		if i > start {
+			buf.WriteString(s[start:i])
+		}
+		buf.WriteByte('%')
+		buf.WriteByte(hexDigits[s[i]>>4])
+		buf.WriteByte(hexDigits[s[i]&0x0f])
+		start = i + 1
+	}
+	if start < len(s) {
+		buf.WriteString(s[start:])
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Expander is implemented by types that want to control how they are
+// rendered into a URI, rather than being passed through
+// fmt.Sprintf("%v", ...). It is checked by TemplatePart.expand before any
+// of the built-in string/array/map/struct handling.
+//
+// term and part describe the variable being expanded (including its
+// Explode and Truncate modifiers), so an Expander can honor them if it
+// makes sense for the type; ExpandURI's return value is still escaped and
+// (if applicable) truncated and named like a string value would be.
+type Expander interface {
+	ExpandURI(term TemplateTerm, part TemplatePart) (string, error)
+}
+
+var (
+	typeExpandersMu sync.RWMutex
+	typeExpanders   = make(map[reflect.Type]func(TemplateTerm, TemplatePart, interface{}) (string, error))
+)
+
+// RegisterTypeExpander registers a rendering function for values of type t,
+// for use by callers who cannot implement Expander directly on t (for
+// example a type from another package, such as time.Time). It is consulted
+// by TemplatePart.expand after the Expander interface and before the
+// default fmt.Sprintf("%v", ...) fallback.
+func RegisterTypeExpander(t reflect.Type, fn func(TemplateTerm, TemplatePart, interface{}) (string, error)) {
+	typeExpandersMu.Lock()
+	defer typeExpandersMu.Unlock()
+	typeExpanders[t] = fn
+}
+
+func lookupTypeExpander(t reflect.Type) (fn func(TemplateTerm, TemplatePart, interface{}) (string, error), ok bool) {
+	typeExpandersMu.RLock()
+	defer typeExpandersMu.RUnlock()
+	fn, ok = typeExpanders[t]
+	return fn, ok
+}
@@ -0,0 +1,125 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// upperExpander renders itself in upper case, so tests can tell whether
+// ExpandURI was actually consulted instead of the fmt.Sprintf fallback.
+type upperExpander string
+
+func (u upperExpander) ExpandURI(term TemplateTerm, part TemplatePart) (string, error) {
+	return strings.ToUpper(string(u)), nil
+}
+
+func TestExpandCustomExpander(t *testing.T) {
+	template, err := Parse("/s{/name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := template.Expand(map[string]interface{}{
+		"name": upperExpander("joshua"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded != "/s/JOSHUA" {
+		t.Errorf("Expand() = %q, want %q", expanded, "/s/JOSHUA")
+	}
+}
+
+func TestExpandCustomExpanderTruncate(t *testing.T) {
+	template, err := Parse("/s{/name:3}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := template.Expand(map[string]interface{}{
+		"name": upperExpander("joshua"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded != "/s/JOS" {
+		t.Errorf("Expand() = %q, want %q", expanded, "/s/JOS")
+	}
+}
+
+func TestExpandCustomExpanderExplode(t *testing.T) {
+	template, err := Parse("/s{/list*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := template.Expand(map[string]interface{}{
+		"list": []interface{}{upperExpander("red"), upperExpander("green"), upperExpander("blue")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded != "/s/RED/GREEN/BLUE" {
+		t.Errorf("Expand() = %q, want %q", expanded, "/s/RED/GREEN/BLUE")
+	}
+}
+
+// errExpander always fails, so callers can confirm ExpandURI errors
+// propagate instead of being swallowed.
+type errExpander struct{}
+
+var errExpand = errExpanderError("boom")
+
+type errExpanderError string
+
+func (e errExpanderError) Error() string { return string(e) }
+
+func (errExpander) ExpandURI(term TemplateTerm, part TemplatePart) (string, error) {
+	return "", errExpand
+}
+
+func TestExpandCustomExpanderError(t *testing.T) {
+	template, err := Parse("/s{/name}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = template.Expand(map[string]interface{}{"name": errExpander{}})
+	if err != errExpand {
+		t.Errorf("Expand() error = %v, want %v", err, errExpand)
+	}
+}
+
+func TestExpandCustomExpanderExplodeError(t *testing.T) {
+	template, err := Parse("/s{/list*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = template.Expand(map[string]interface{}{"list": []interface{}{errExpander{}}})
+	if err != errExpand {
+		t.Errorf("Expand() error = %v, want %v", err, errExpand)
+	}
+}
+
+// duration renders a time.Duration-like value via RegisterTypeExpander
+// rather than implementing Expander itself, as for a type the caller does
+// not own.
+type duration int64
+
+func TestRegisterTypeExpander(t *testing.T) {
+	RegisterTypeExpander(reflect.TypeOf(duration(0)), func(term TemplateTerm, part TemplatePart, value interface{}) (string, error) {
+		return "dur", nil
+	})
+	template, err := Parse("/s{/d}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := template.Expand(map[string]interface{}{"d": duration(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expanded != "/s/dur" {
+		t.Errorf("Expand() = %q, want %q", expanded, "/s/dur")
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matchTerm records which TemplatePart and TemplateTerm a capture group in
+// the generated matching regular expression corresponds to.
+type matchTerm struct {
+	part TemplatePart
+	term TemplateTerm
+}
+
+// Match inverts Expand: given a concrete URI that was presumably produced by
+// expanding this template, it extracts the values that would have been
+// passed to Expand and returns them as a map. For example, matching
+// "https://api.github.com/repos/jtacoma/uritemplates" against the template
+// "https://api.github.com/repos{/user,repo}" returns
+// {"user": "jtacoma", "repo": "uritemplates"}.
+//
+// Match assumes every variable in the template is present in the uri; it
+// does not attempt to infer which optional variables were left out of an
+// expansion. The regular expression used to match is built once per
+// template and cached.
+func (self *UriTemplate) Match(uri string) (map[string]interface{}, error) {
+	re, terms, err := self.matcher()
+	if err != nil {
+		return nil, err
+	}
+	groups := re.FindStringSubmatch(uri)
+	if groups == nil {
+		return nil, errors.New("uritemplates: uri does not match template " + self.Raw)
+	}
+	values := make(map[string]interface{})
+	for i, mt := range terms {
+		raw := groups[i+1]
+		if mt.term.Explode {
+			pieces := strings.Split(raw, mt.part.Sep)
+			decoded := make([]interface{}, len(pieces))
+			for j, piece := range pieces {
+				// named explode repeats "name=" before every element
+				// (e.g. "list=x&list=y"); strip it from each piece.
+				if mt.part.Named {
+					if idx := strings.IndexByte(piece, '='); idx >= 0 {
+						piece = piece[idx+1:]
+					}
+				}
+				d, err := url.PathUnescape(piece)
+				if err != nil {
+					return nil, err
+				}
+				decoded[j] = d
+			}
+			values[mt.term.Name] = decoded
+			continue
+		}
+		decoded, err := url.PathUnescape(raw)
+		if err != nil {
+			return nil, err
+		}
+		values[mt.term.Name] = decoded
+	}
+	return values, nil
+}
+
+// matcher returns the compiled regular expression and its capture-group
+// bookkeeping, building and caching them on the first call.
+func (self *UriTemplate) matcher() (*regexp.Regexp, []matchTerm, error) {
+	self.matchOnce.Do(func() {
+		self.matchRegex, self.matchTerms, self.matchBuildErr = self.buildMatcher()
+	})
+	return self.matchRegex, self.matchTerms, self.matchBuildErr
+}
+
+func (self *UriTemplate) buildMatcher() (re *regexp.Regexp, terms []matchTerm, err error) {
+	var pattern bytes.Buffer
+	pattern.WriteString("^")
+	for i, p := range self.Parts {
+		if len(p.Raw) > 0 || len(p.Terms) == 0 {
+			pattern.WriteString(regexp.QuoteMeta(p.Raw))
+			continue
+		}
+		pattern.WriteString(regexp.QuoteMeta(p.First))
+		for ti, term := range p.Terms {
+			if ti > 0 {
+				pattern.WriteString(regexp.QuoteMeta(p.Sep))
+			}
+			// Named explode (e.g. "?list*") repeats "name=" before every
+			// element inside the captured run itself, so the literal
+			// name= is not emitted up front the way it is for a
+			// non-exploded named term.
+			if p.Named && !term.Explode {
+				pattern.WriteString(regexp.QuoteMeta(term.Name))
+				pattern.WriteString("=")
+			}
+			last := ti == len(p.Terms)-1
+			if last && !p.Named && followedByUnnamedTerm(self.Parts, i) {
+				err = errors.New("uritemplates: ambiguous template, cannot match: adjacent unnamed variables with no separator")
+				return
+			}
+			pattern.WriteString(captureGroup(p, term))
+			terms = append(terms, matchTerm{part: p, term: term})
+		}
+	}
+	pattern.WriteString("$")
+	re, err = regexp.Compile(pattern.String())
+	if err != nil {
+		re = nil
+		terms = nil
+	}
+	return
+}
+
+// captureGroup builds the capture group for a single term, bounded by the
+// term's truncate length when present. The character class comes from
+// matchCharClass, which reflects AllowReserved (reserved-set) vs the
+// default (unreserved-set) and, for exploded terms, additionally admits the
+// part's separator since an exploded capture spans a whole Sep-joined run
+// rather than a single value.
+func captureGroup(p TemplatePart, t TemplateTerm) string {
+	class := matchCharClass(p, t.Explode)
+	quantifier := "+"
+	if p.Ifemp != "" {
+		quantifier = "*"
+	}
+	if !t.Explode && t.Truncate > 0 {
+		quantifier = "{1," + strconv.Itoa(t.Truncate) + "}"
+	}
+	return "(" + class + quantifier + ")"
+}
+
+// matchCharClass builds the regexp character class for a captured value:
+// the unreserved set, or the reserved set when p.AllowReserved is true
+// (mirroring the same tables escapeTo uses), always admitting '%' so
+// percent-encoded bytes are matched. A single-value capture additionally
+// excludes the part's separator so it stops at the boundary with the next
+// term; an exploded capture instead includes the separator (and, for named
+// explode, '=') since it must span the whole "a=1&a=2"-style run, which is
+// split apart afterward in Match.
+func matchCharClass(p TemplatePart, explode bool) string {
+	table := unreservedTable
+	if p.AllowReserved {
+		table = reservedTable
+	}
+	var sepByte byte
+	hasSep := len(p.Sep) == 1
+	if hasSep {
+		sepByte = p.Sep[0]
+	}
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for b := 0; b < 256; b++ {
+		allowed := table[b] || b == '%'
+		if hasSep && byte(b) == sepByte {
+			allowed = explode
+		}
+		if explode && p.Named && byte(b) == '=' {
+			allowed = true
+		}
+		if allowed {
+			// regexp.QuoteMeta does not escape '-', which is only special
+			// inside a character class (where it forms a range with its
+			// neighbors unless escaped or positioned first/last); every
+			// other byte it can return here is already safe at any
+			// position within one. Escape it explicitly so ascending
+			// bytes around it (e.g. '%' and '.') can't combine into an
+			// unintended range like "%-.".
+			if b == '-' {
+				buf.WriteString("\\-")
+			} else {
+				buf.WriteString(regexp.QuoteMeta(string(byte(b))))
+			}
+		}
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// followedByUnnamedTerm reports whether the next expression after
+// self.Parts[i] is itself an unnamed variable with no literal text between
+// them and no leading operator character that the last capture of
+// self.Parts[i] stops at, which would make the boundary between the two
+// ambiguous to match.
+// Parse always interleaves a literal part (often empty) between adjacent
+// expressions, so an empty one is skipped over to find the real neighbor.
+func followedByUnnamedTerm(parts []TemplatePart, i int) bool {
+	j := i + 1
+	if j < len(parts) && len(parts[j].Raw) == 0 && len(parts[j].Terms) == 0 {
+		j++
+	}
+	if j >= len(parts) {
+		return false
+	}
+	next := parts[j]
+	if len(next.Raw) != 0 || len(next.Terms) == 0 || next.Named {
+		return false
+	}
+	if next.First == "" {
+		return true
+	}
+	// Same admission rules as matchCharClass for an unnamed part.
+	cur := parts[i]
+	b := next.First[0]
+	if len(cur.Sep) == 1 && b == cur.Sep[0] {
+		return cur.Terms[len(cur.Terms)-1].Explode
+	}
+	if cur.AllowReserved {
+		return reservedTable[b]
+	}
+	return unreservedTable[b]
+}
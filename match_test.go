@@ -0,0 +1,95 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchSimple(t *testing.T) {
+	template, err := Parse("https://api.github.com/repos{/user,repo}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := template.Match("https://api.github.com/repos/jtacoma/uritemplates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"user": "jtacoma", "repo": "uritemplates"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %#v, want %#v", got, want)
+	}
+}
+
+func TestMatchExplodePathList(t *testing.T) {
+	template, err := Parse("/s{/list*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := template.Match("/s/red/green/blue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"list": []interface{}{"red", "green", "blue"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %#v, want %#v", got, want)
+	}
+}
+
+func TestMatchExplodeNamedQueryList(t *testing.T) {
+	template, err := Parse("/s{?list*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := template.Match("/s?list=x&list=y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"list": []interface{}{"x", "y"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %#v, want %#v", got, want)
+	}
+}
+
+func TestMatchReservedCharacters(t *testing.T) {
+	template, err := Parse("/s{+path}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := template.Match("/s/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"path": "/foo/bar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %#v, want %#v", got, want)
+	}
+}
+
+func TestMatchCharClassExcludesReservedAndSep(t *testing.T) {
+	template, err := Parse("{a}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A single unnamed term's class must stop at the default separator
+	// (",") and at reserved characters; none of these bytes should be
+	// absorbed into the capture.
+	for _, uri := range []string{"x,y", "x&y", "x+y", "x(y)", "x*y"} {
+		if _, err := template.Match(uri); err == nil {
+			t.Errorf("Match(%q) = nil error, want error", uri)
+		}
+	}
+}
+
+func TestMatchAmbiguousTemplate(t *testing.T) {
+	template, err := Parse("{a}{b}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := template.Match("helloworld"); err == nil {
+		t.Error("Match() on an ambiguous template returned nil error, want an error")
+	}
+}
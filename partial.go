@@ -0,0 +1,177 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ExpandPartial expands only the variables present in values, returning a
+// new UriTemplate in which every variable missing from values (or mapped to
+// a nil value) is left in place as an unexpanded {...} expression. This is
+// useful for API clients that assemble a base URL at configuration time
+// (e.g. a server root and version) and fill in per-request variables later,
+// without having to re-parse the original template string.
+func (self *UriTemplate) ExpandPartial(value interface{}) (*UriTemplate, error) {
+	values, ismap := value.(map[string]interface{})
+	if !ismap {
+		if m, ismap := struct2map(value); !ismap {
+			return nil, errors.New("expected map[string]interface{}, struct, or pointer to struct.")
+		} else {
+			return self.ExpandPartial(m)
+		}
+	}
+	result := new(UriTemplate)
+	for _, p := range self.Parts {
+		expanded, err := p.expandPartial(values)
+		if err != nil {
+			return nil, err
+		}
+		result.Parts = append(result.Parts, expanded...)
+	}
+	var raw bytes.Buffer
+	for _, p := range result.Parts {
+		raw.WriteString(p.raw())
+	}
+	result.Raw = raw.String()
+	return result, nil
+}
+
+// expandPartial expands the terms of self that have a value in values and
+// re-serializes the remaining ones into a new expression part, returning
+// the literal text and/or the leftover expression as separate parts.
+func (self *TemplatePart) expandPartial(values map[string]interface{}) ([]TemplatePart, error) {
+	if len(self.Raw) > 0 || len(self.Terms) == 0 {
+		return []TemplatePart{*self}, nil
+	}
+	var missing []string
+	present := 0
+	for _, term := range self.Terms {
+		value, exists := values[term.Name]
+		if !exists || value == nil {
+			missing = append(missing, termToString(term))
+		} else {
+			present++
+		}
+	}
+	var buf bytes.Buffer
+	if err := self.expand(&buf, values, nil); err != nil {
+		return nil, err
+	}
+	parts := make([]TemplatePart, 0, 2)
+	if buf.Len() > 0 {
+		parts = append(parts, TemplatePart{Raw: buf.String()})
+	}
+	if len(missing) > 0 {
+		part, err := parseExpression(operatorPrefix(*self) + strings.Join(missing, ","))
+		if err != nil {
+			return nil, err
+		}
+		if present > 0 || self.continuation {
+			// Some terms of this expression were already emitted above;
+			// the leftover terms continue that same group rather than
+			// starting a fresh one, so they must be joined with the
+			// separator instead of repeating the operator's leading
+			// literal ("?a,b" split after "a" continues as "&b", not a
+			// second "?b"). A part that was already such a continuation
+			// stays one, keeping the separator and AllowReserved that
+			// operatorPrefix cannot recover from its demoted First.
+			part.First = self.Sep
+			part.AllowReserved = self.AllowReserved
+			part.continuation = true
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
+
+// raw renders a TemplatePart back to the template syntax it was parsed
+// from: the literal text itself for a literal part, or a {op term,...}
+// expression for a part still holding unexpanded terms.
+func (self TemplatePart) raw() string {
+	if len(self.Raw) > 0 || len(self.Terms) == 0 {
+		return self.Raw
+	}
+	names := make([]string, len(self.Terms))
+	for i, term := range self.Terms {
+		names[i] = termToString(term)
+	}
+	joined := strings.Join(names, ",")
+	if self.continuation && !isOperatorPrefixChar(self.First) {
+		// First was demoted to the separator of the default, "+" or "#"
+		// operator (all three share Sep == ","), none of which has a
+		// {op term} syntax of its own to carry a leading "," - unlike
+		// ";", "?" and "&", whose continuation separator IS one of their
+		// own valid operator characters. Emit that separator as literal
+		// text instead, followed by a bare expression that reproduces
+		// the same AllowReserved behavior, so the rendered Raw expands
+		// to the same string as the live Parts it was built from whenever
+		// at least one of these terms has a value. When none does, the
+		// live Parts drop the separator along with the expression, while
+		// Raw keeps it as literal text.
+		prefix := ""
+		if self.AllowReserved {
+			prefix = "+"
+		}
+		return self.First + "{" + prefix + joined + "}"
+	}
+	return "{" + operatorPrefix(self) + joined + "}"
+}
+
+// isOperatorPrefixChar reports whether s is one of the operator characters
+// that can appear literally at the start of a {op term,...} expression.
+func isOperatorPrefixChar(s string) bool {
+	switch s {
+	case ".", "/", ";", "?", "&", "#":
+		return true
+	}
+	return false
+}
+
+// termToString re-serializes a single TemplateTerm back to its expression
+// syntax, e.g. "name", "name:3" or "name*".
+func termToString(term TemplateTerm) string {
+	s := term.Name
+	if term.Truncate > 0 {
+		s += ":" + strconv.Itoa(term.Truncate)
+	}
+	if term.Explode {
+		s += "*"
+	}
+	return s
+}
+
+// operatorPrefix recovers the operator character that parseExpression
+// consumed to produce the given part's First/Sep/Named/Ifemp/AllowReserved
+// combination, or "" for the default (simple string expansion) operator.
+// For a part whose First was demoted to its separator by expandPartial's
+// continuation handling, this is only correct when that separator is
+// itself a valid operator character (";", "?" and "&" continue as ";",
+// "&" and "&" respectively); raw() checks isOperatorPrefixChar first and
+// takes a different path for the default, "+" and "#" operators, whose
+// shared "," separator has no {op term} syntax of its own.
+func operatorPrefix(p TemplatePart) string {
+	switch {
+	case p.First == "" && p.AllowReserved:
+		return "+"
+	case p.First == ".":
+		return "."
+	case p.First == "/":
+		return "/"
+	case p.First == ";":
+		return ";"
+	case p.First == "?":
+		return "?"
+	case p.First == "&":
+		return "&"
+	case p.First == "#":
+		return "#"
+	default:
+		return ""
+	}
+}
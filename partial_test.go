@@ -0,0 +1,119 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import "testing"
+
+// expandRaw re-parses a UriTemplate's Raw string and expands it, so tests
+// can confirm Raw and the live Parts it was built from expand identically.
+func expandRaw(t *testing.T, raw string, values map[string]interface{}) string {
+	t.Helper()
+	reparsed, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse(%q) = %v", raw, err)
+	}
+	expanded, err := reparsed.Expand(values)
+	if err != nil {
+		t.Fatalf("Expand() on reparsed Raw = %v", err)
+	}
+	return expanded
+}
+
+func TestExpandPartialBasic(t *testing.T) {
+	template, err := Parse("https://api.github.com/repos{/user,repo}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := template.ExpandPartial(map[string]interface{}{"user": "jtacoma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := partial.Expand(map[string]interface{}{"repo": "uritemplates"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://api.github.com/repos/jtacoma/uritemplates"
+	if expanded != want {
+		t.Errorf("Expand() = %q, want %q", expanded, want)
+	}
+	if got := expandRaw(t, partial.Raw, map[string]interface{}{"repo": "uritemplates"}); got != want {
+		t.Errorf("Raw round-trip = %q, want %q", got, want)
+	}
+}
+
+// TestExpandPartialContinuationOperators covers every operator whose
+// Sep/First combination can arise as a split expression's leftover part,
+// checking that the live Parts and a re-parsed Raw expand identically.
+func TestExpandPartialContinuationOperators(t *testing.T) {
+	cases := []struct {
+		name     string
+		template string
+		have     map[string]interface{}
+		missing  map[string]interface{}
+		want     string
+	}{
+		{"default", "{a,b}", map[string]interface{}{"a": "AV"}, map[string]interface{}{"b": "BV"}, "AV,BV"},
+		{"reserved", "{+a,b}", map[string]interface{}{"a": "/x/y"}, map[string]interface{}{"b": "/p/q"}, "/x/y,/p/q"},
+		{"fragment", "{#a,b}", map[string]interface{}{"a": "x"}, map[string]interface{}{"b": "y"}, "#x,y"},
+		{"label", "{.a,b}", map[string]interface{}{"a": "x"}, map[string]interface{}{"b": "y"}, ".x.y"},
+		{"path", "{/a,b}", map[string]interface{}{"a": "x"}, map[string]interface{}{"b": "y"}, "/x/y"},
+		{"matrix", "{;a,b}", map[string]interface{}{"a": "x"}, map[string]interface{}{"b": "y"}, ";a=x;b=y"},
+		{"query", "/base{?a,b}", map[string]interface{}{"a": "1"}, map[string]interface{}{"b": "2"}, "/base?a=1&b=2"},
+		{"continuation", "{&a,b}", map[string]interface{}{"a": "1"}, map[string]interface{}{"b": "2"}, "&a=1&b=2"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			template, err := Parse(c.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			partial, err := template.ExpandPartial(c.have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotFromParts, err := partial.Expand(c.missing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotFromParts != c.want {
+				t.Errorf("Expand() on Parts = %q, want %q", gotFromParts, c.want)
+			}
+			if gotFromRaw := expandRaw(t, partial.Raw, c.missing); gotFromRaw != c.want {
+				t.Errorf("Expand() on reparsed Raw (%q) = %q, want %q", partial.Raw, gotFromRaw, c.want)
+			}
+		})
+	}
+}
+
+func TestExpandPartialAllPresentLeavesNoExpression(t *testing.T) {
+	template, err := Parse("{a,b}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := template.ExpandPartial(map[string]interface{}{"a": "AV", "b": "BV"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.Raw != "AV,BV" {
+		t.Errorf("Raw = %q, want %q", partial.Raw, "AV,BV")
+	}
+	if len(partial.Names()) != 0 {
+		t.Errorf("Names() = %v, want none left unexpanded", partial.Names())
+	}
+}
+
+func TestExpandPartialNonePresentKeepsExpression(t *testing.T) {
+	template, err := Parse("{?a,b}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := template.ExpandPartial(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.Raw != "{?a,b}" {
+		t.Errorf("Raw = %q, want %q", partial.Raw, "{?a,b}")
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+// partPlan caches a TemplatePart's literal text and the leading/separator
+// strings of its expression as byte slices, computed once per UriTemplate
+// instead of being re-derived from the part's string fields on every
+// Expand/ExpandTo call. Term lookups are deliberately left out of the plan:
+// a TemplateTerm is read directly from the parsed expression with no
+// per-call conversion to cache, so precomputing anything about it would add
+// bookkeeping without removing any work.
+type partPlan struct {
+	literal []byte
+	first   []byte
+	sep     []byte
+}
+
+// plan returns the per-part expansion plan for self, building and caching
+// it on the first call the same way matcher (see match.go) caches the
+// regular expression built for Match.
+func (self *UriTemplate) plan() []partPlan {
+	self.planOnce.Do(func() {
+		self.compiledPlan = make([]partPlan, len(self.Parts))
+		for i, p := range self.Parts {
+			self.compiledPlan[i] = partPlan{
+				literal: []byte(p.Raw),
+				first:   []byte(p.First),
+				sep:     []byte(p.Sep),
+			}
+		}
+	})
+	return self.compiledPlan
+}
+
+// ParseCompiled is like Parse, but eagerly builds the expansion plan that
+// Expand/ExpandTo would otherwise build lazily on their first call. Prefer
+// it for a template that is parsed once (e.g. into a package-level var) and
+// expanded repeatedly on a hot path, so that the first expansion is not the
+// one that pays the plan's one-time cost.
+func ParseCompiled(rawtemplate string) (*UriTemplate, error) {
+	template, err := Parse(rawtemplate)
+	if err != nil {
+		return nil, err
+	}
+	template.plan()
+	return template, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import "reflect"
+
+// toInterfaceSlice reflects over any slice or array value (e.g. []string,
+// []int, [3]bool) and copies it into a []interface{}, so that callers are
+// not required to build that conversion themselves before calling Expand.
+func toInterfaceSlice(value interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		result := make([]interface{}, rv.Len())
+		for i := range result {
+			result[i] = rv.Index(i).Interface()
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// toStringKeyedMap reflects over any map whose key type is a string (or a
+// defined type with string underlying kind, e.g. type Key string) and
+// copies it into a map[string]interface{}.
+func toStringKeyedMap(value interface{}) (map[string]interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	result := make(map[string]interface{}, rv.Len())
+	for _, key := range rv.MapKeys() {
+		result[key.String()] = rv.MapIndex(key).Interface()
+	}
+	return result, true
+}
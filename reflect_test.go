@@ -0,0 +1,185 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToInterfaceSliceTypedSlice(t *testing.T) {
+	got, ok := toInterfaceSlice([]string{"a", "b", "c"})
+	if !ok {
+		t.Fatal("toInterfaceSlice([]string) = false, want true")
+	}
+	want := []interface{}{"a", "b", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toInterfaceSlice() = %#v, want %#v", got, want)
+	}
+}
+
+func TestToInterfaceSliceTypedArray(t *testing.T) {
+	got, ok := toInterfaceSlice([3]int{1, 2, 3})
+	if !ok {
+		t.Fatal("toInterfaceSlice([3]int) = false, want true")
+	}
+	want := []interface{}{1, 2, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toInterfaceSlice() = %#v, want %#v", got, want)
+	}
+}
+
+func TestToInterfaceSliceRejectsNonSlice(t *testing.T) {
+	if _, ok := toInterfaceSlice("not a slice"); ok {
+		t.Error("toInterfaceSlice(string) = true, want false")
+	}
+}
+
+func TestToStringKeyedMapStringKeys(t *testing.T) {
+	got, ok := toStringKeyedMap(map[string]int{"a": 1, "b": 2})
+	if !ok {
+		t.Fatal("toStringKeyedMap(map[string]int) = false, want true")
+	}
+	want := map[string]interface{}{"a": 1, "b": 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toStringKeyedMap() = %#v, want %#v", got, want)
+	}
+}
+
+type namedStringKey string
+
+func TestToStringKeyedMapDefinedStringKeyType(t *testing.T) {
+	got, ok := toStringKeyedMap(map[namedStringKey]int{"a": 1})
+	if !ok {
+		t.Fatal("toStringKeyedMap(map[namedStringKey]int) = false, want true")
+	}
+	want := map[string]interface{}{"a": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toStringKeyedMap() = %#v, want %#v", got, want)
+	}
+}
+
+func TestToStringKeyedMapRejectsNonStringKeys(t *testing.T) {
+	if _, ok := toStringKeyedMap(map[int]string{1: "a"}); ok {
+		t.Error("toStringKeyedMap(map[int]string) = true, want false")
+	}
+}
+
+func TestExpandTypedSlice(t *testing.T) {
+	template, err := Parse("/s{/list*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := template.Expand(map[string]interface{}{"list": []string{"red", "green", "blue"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/s/red/green/blue"; expanded != want {
+		t.Errorf("Expand() = %q, want %q", expanded, want)
+	}
+}
+
+func TestExpandTypedMap(t *testing.T) {
+	template, err := Parse("/s{?keys*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expanded, err := template.Expand(map[string]interface{}{"keys": map[string]int{"a": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/s?a=1"; expanded != want {
+		t.Errorf("Expand() = %q, want %q", expanded, want)
+	}
+}
+
+type address struct {
+	City string
+	Zip  string `uri:"zip"`
+}
+
+type person struct {
+	Name    string
+	Address address
+	Nick    *string `uri:"nick,omitempty"`
+}
+
+func TestStruct2MapNestedStruct(t *testing.T) {
+	p := person{Name: "Josh", Address: address{City: "Seattle", Zip: "98101"}}
+	m, ok := struct2map(p)
+	if !ok {
+		t.Fatal("struct2map(person) = false, want true")
+	}
+	if m["Name"] != "Josh" {
+		t.Errorf("m[\"Name\"] = %v, want %q", m["Name"], "Josh")
+	}
+	addr, ok := m["Address"].(address)
+	if !ok {
+		t.Fatalf("m[\"Address\"] = %#v, want an address", m["Address"])
+	}
+	if addr.City != "Seattle" {
+		t.Errorf("addr.City = %q, want %q", addr.City, "Seattle")
+	}
+	if _, present := m["nick"]; present {
+		t.Errorf("m[\"nick\"] present = true, want omitted (nil pointer + omitempty)")
+	}
+}
+
+func TestStruct2MapPointerToStruct(t *testing.T) {
+	p := &person{Name: "Josh", Address: address{City: "Seattle", Zip: "98101"}}
+	m, ok := struct2map(p)
+	if !ok {
+		t.Fatal("struct2map(*person) = false, want true")
+	}
+	if m["Name"] != "Josh" {
+		t.Errorf("m[\"Name\"] = %v, want %q", m["Name"], "Josh")
+	}
+}
+
+func TestStruct2MapNilPointerRejected(t *testing.T) {
+	var p *person
+	if _, ok := struct2map(p); ok {
+		t.Error("struct2map((*person)(nil)) = true, want false")
+	}
+}
+
+func TestStruct2MapOmitemptySetVsZero(t *testing.T) {
+	nick := "jt"
+	withNick, ok := struct2map(person{Name: "Josh", Nick: &nick})
+	if !ok {
+		t.Fatal("struct2map(person) = false, want true")
+	}
+	if withNick["nick"] != &nick {
+		t.Errorf("withNick[\"nick\"] = %v, want pointer to %q", withNick["nick"], nick)
+	}
+
+	withoutNick, ok := struct2map(person{Name: "Josh"})
+	if !ok {
+		t.Fatal("struct2map(person) = false, want true")
+	}
+	if _, present := withoutNick["nick"]; present {
+		t.Error("withoutNick[\"nick\"] present = true, want omitted (zero value + omitempty)")
+	}
+}
+
+func TestExpandNestedStruct(t *testing.T) {
+	template, err := Parse("/s{/name}{?address*}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type zipOnly struct {
+		Zip string `uri:"zip"`
+	}
+	expanded, err := template.Expand(struct {
+		Name    string  `uri:"name"`
+		Address zipOnly `uri:"address"`
+	}{Name: "josh", Address: zipOnly{Zip: "98101"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/s/josh?zip=98101"; expanded != want {
+		t.Errorf("Expand() = %q, want %q", expanded, want)
+	}
+}
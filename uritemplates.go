@@ -25,39 +25,29 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
-var (
-	unreserved = regexp.MustCompile("[^A-Za-z0-9\\-._~]")
-	reserved   = regexp.MustCompile("[^A-Za-z0-9\\-._~:/?#[\\]@!$&'()*+,;=]")
-	validname  = regexp.MustCompile("^([A-Za-z0-9_\\.]|%[0-9A-Fa-f][0-9A-Fa-f])+$")
-	hex        = []byte("0123456789ABCDEF")
-)
-
-func pctEncode(src []byte) []byte {
-	dst := make([]byte, len(src)*3)
-	for i, b := range src {
-		buf := dst[i*3 : i*3+3]
-		buf[0] = 0x25
-		buf[1] = hex[b/16]
-		buf[2] = hex[b%16]
-	}
-	return dst
-}
-
-func escape(s string, allowReserved bool) (escaped string) {
-	if allowReserved {
-		escaped = string(reserved.ReplaceAllFunc([]byte(s), pctEncode))
-	} else {
-		escaped = string(unreserved.ReplaceAllFunc([]byte(s), pctEncode))
-	}
-	return escaped
-}
+var validname = regexp.MustCompile("^([A-Za-z0-9_\\.]|%[0-9A-Fa-f][0-9A-Fa-f])+$")
 
 // A UriTemplate is a parsed representation of a URI template.
 type UriTemplate struct {
 	Parts []TemplatePart
 	Raw   string
+
+	// matchRegex and matchTerms cache the regular expression built by
+	// Match so that repeated calls against the same template do not
+	// repay the cost of compiling it.
+	matchOnce     sync.Once
+	matchRegex    *regexp.Regexp
+	matchTerms    []matchTerm
+	matchBuildErr error
+
+	// planOnce and compiledPlan cache the per-part expansion plan built by
+	// plan (see plan.go) so that repeated Expand/ExpandTo calls against the
+	// same template do not repay the cost of building it.
+	planOnce     sync.Once
+	compiledPlan []partPlan
 }
 
 // Parse parses a URI template string into a UriTemplate object.
@@ -93,10 +83,20 @@ func Parse(rawtemplate string) (template *UriTemplate, err error) {
 	return template, err
 }
 
-func (t UriTemplate) String() string {
+func (t *UriTemplate) String() string {
 	return t.Raw
 }
 
+// MustParse is like Parse but panics if the template cannot be parsed,
+// simplifying safe initialization of global variables holding templates.
+func MustParse(rawtemplate string) *UriTemplate {
+	template, err := Parse(rawtemplate)
+	if err != nil {
+		panic(err)
+	}
+	return template
+}
+
 type TemplatePart struct {
 	Terms         []TemplateTerm
 	Raw           string
@@ -105,6 +105,14 @@ type TemplatePart struct {
 	Named         bool
 	Ifemp         string
 	AllowReserved bool
+
+	// continuation marks a part built by ExpandPartial's expandPartial,
+	// whose First was demoted from the expression's original operator
+	// prefix to its separator because some of the expression's terms were
+	// already expanded ahead of it (see partial.go). raw() consults this
+	// to decide whether First can be re-derived via operatorPrefix as-is
+	// or needs to be carried as a literal instead.
+	continuation bool
 }
 
 type TemplateTerm struct {
@@ -207,31 +215,37 @@ func (self *UriTemplate) Names() []string {
 
 // Expand expands a URI template with a set of values to produce a string.
 func (self *UriTemplate) Expand(value interface{}) (string, error) {
-	values, ismap := value.(map[string]interface{})
-	if !ismap {
-		if m, ismap := struct2map(value); !ismap {
-			return "", errors.New("expected map[string]interface{}, struct, or pointer to struct.")
-		} else {
-			return self.Expand(m)
-		}
-	}
-	var buf bytes.Buffer
-	for _, p := range self.Parts {
-		err := p.expand(&buf, values)
-		if err != nil {
-			return "", err
-		}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	if _, err := self.ExpandTo(buf, value); err != nil {
+		return "", err
 	}
 	return buf.String(), nil
 }
 
-func (self *TemplatePart) expand(buf *bytes.Buffer, values map[string]interface{}) error {
-	if len(self.Raw) > 0 {
+// expand renders self to buf for the given values. pp is the part's
+// precompiled plan (see plan.go); when non-nil, the literal text and the
+// expression's leading/separator strings are copied from it as byte slices
+// instead of being re-derived from self's string fields on every call. Pass
+// nil for one-off expansion (e.g. from ExpandPartial) where no plan has
+// been built.
+func (self *TemplatePart) expand(buf *bytes.Buffer, values map[string]interface{}, pp *partPlan) error {
+	if pp != nil {
+		if len(pp.literal) > 0 {
+			buf.Write(pp.literal)
+			return nil
+		}
+	} else if len(self.Raw) > 0 {
 		buf.WriteString(self.Raw)
 		return nil
 	}
 	var zeroLen = buf.Len()
-	buf.WriteString(self.First)
+	if pp != nil {
+		buf.Write(pp.first)
+	} else {
+		buf.WriteString(self.First)
+	}
 	var firstLen = buf.Len()
 	for _, term := range self.Terms {
 		value, exists := values[term.Name]
@@ -247,20 +261,49 @@ func (self *TemplatePart) expand(buf *bytes.Buffer, values map[string]interface{
 		}
 
 		if buf.Len() != firstLen {
-			buf.WriteString(self.Sep)
+			if pp != nil {
+				buf.Write(pp.sep)
+			} else {
+				buf.WriteString(self.Sep)
+			}
+		}
+		if expander, ok := value.(Expander); ok {
+			str, err := expander.ExpandURI(term, *self)
+			if err != nil {
+				return err
+			}
+			self.expandString(buf, term, str)
+			continue
 		}
 		switch v := value.(type) {
 		case string:
 			self.expandString(buf, term, v)
 		case []interface{}:
-			self.expandArray(buf, term, v)
+			if err := self.expandArray(buf, term, v); err != nil {
+				return err
+			}
 		case map[string]interface{}:
 			if term.Truncate > 0 {
 				return errors.New("cannot truncate a map expansion")
 			}
 			self.expandMap(buf, term, v)
 		default:
-			if m, ismap := struct2map(value); ismap {
+			if fn, ok := lookupTypeExpander(reflect.TypeOf(value)); ok {
+				str, err := fn(term, *self, value)
+				if err != nil {
+					return err
+				}
+				self.expandString(buf, term, str)
+			} else if m, ismap := struct2map(value); ismap {
+				if term.Truncate > 0 {
+					return errors.New("cannot truncate a map expansion")
+				}
+				self.expandMap(buf, term, m)
+			} else if a, isslice := toInterfaceSlice(value); isslice {
+				if err := self.expandArray(buf, term, a); err != nil {
+					return err
+				}
+			} else if m, ismap := toStringKeyedMap(value); ismap {
 				if term.Truncate > 0 {
 					return errors.New("cannot truncate a map expansion")
 				}
@@ -295,12 +338,12 @@ func (self *TemplatePart) expandString(buf *bytes.Buffer, t TemplateTerm, s stri
 		s = s[:t.Truncate]
 	}
 	self.expandName(buf, t.Name, len(s) == 0)
-	buf.WriteString(escape(s, self.AllowReserved))
+	escapeTo(buf, s, self.AllowReserved)
 }
 
-func (self *TemplatePart) expandArray(buf *bytes.Buffer, t TemplateTerm, a []interface{}) {
+func (self *TemplatePart) expandArray(buf *bytes.Buffer, t TemplateTerm, a []interface{}) error {
 	if len(a) == 0 {
-		return
+		return nil
 	} else if !t.Explode {
 		self.expandName(buf, t.Name, false)
 	}
@@ -311,11 +354,19 @@ func (self *TemplatePart) expandArray(buf *bytes.Buffer, t TemplateTerm, a []int
 			buf.WriteString(",")
 		}
 		var s string
-		switch v := value.(type) {
-		case string:
-			s = v
-		default:
-			s = fmt.Sprintf("%v", v)
+		if expander, ok := value.(Expander); ok {
+			str, err := expander.ExpandURI(t, *self)
+			if err != nil {
+				return err
+			}
+			s = str
+		} else {
+			switch v := value.(type) {
+			case string:
+				s = v
+			default:
+				s = fmt.Sprintf("%v", v)
+			}
 		}
 		if len(s) > t.Truncate && t.Truncate > 0 {
 			s = s[:t.Truncate]
@@ -323,8 +374,9 @@ func (self *TemplatePart) expandArray(buf *bytes.Buffer, t TemplateTerm, a []int
 		if self.Named && t.Explode {
 			self.expandName(buf, t.Name, len(s) == 0)
 		}
-		buf.WriteString(escape(s, self.AllowReserved))
+		escapeTo(buf, s, self.AllowReserved)
 	}
+	return nil
 }
 
 func (self *TemplatePart) expandMap(buf *bytes.Buffer, t TemplateTerm, m map[string]interface{}) {
@@ -351,13 +403,13 @@ func (self *TemplatePart) expandMap(buf *bytes.Buffer, t TemplateTerm, m map[str
 			s = fmt.Sprintf("%v", v)
 		}
 		if t.Explode {
-			buf.WriteString(escape(k, self.AllowReserved))
+			escapeTo(buf, k, self.AllowReserved)
 			buf.WriteRune('=')
-			buf.WriteString(escape(s, self.AllowReserved))
+			escapeTo(buf, s, self.AllowReserved)
 		} else {
-			buf.WriteString(escape(k, self.AllowReserved))
+			escapeTo(buf, k, self.AllowReserved)
 			buf.WriteRune(',')
-			buf.WriteString(escape(s, self.AllowReserved))
+			escapeTo(buf, s, self.AllowReserved)
 		}
 	}
 }
@@ -367,23 +419,67 @@ func struct2map(v interface{}) (map[string]interface{}, bool) {
 
 	switch value.Type().Kind() {
 	case reflect.Ptr:
+		if value.IsNil() {
+			return nil, false
+		}
 		return struct2map(value.Elem().Interface())
 	case reflect.Struct:
 		m := make(map[string]interface{})
 		for i := 0; i < value.NumField(); i++ {
 			tag := value.Type().Field(i).Tag
-			var name string
+			var tagValue string
 			if strings.Contains(string(tag), ":") {
-				name = tag.Get("uri")
+				tagValue = tag.Get("uri")
 			} else {
-				name = strings.TrimSpace(string(tag))
+				tagValue = strings.TrimSpace(string(tag))
 			}
+			name, omitempty := parseUriTag(tagValue)
 			if len(name) == 0 {
 				name = value.Type().Field(i).Name
 			}
-			m[name] = value.Field(i).Interface()
+			field := value.Field(i)
+			if omitempty && isEmptyValue(field) {
+				continue
+			}
+			m[name] = field.Interface()
 		}
 		return m, true
 	}
 	return nil, false
 }
+
+// parseUriTag splits a `uri:"name,omitempty"` tag value into the field name
+// and whether the omitempty option was given, mirroring how encoding/json
+// reads its own struct tags.
+func parseUriTag(tagValue string) (name string, omitempty bool) {
+	name = tagValue
+	if idx := strings.Index(tagValue, ","); idx >= 0 {
+		name = tagValue[:idx]
+		for _, opt := range strings.Split(tagValue[idx+1:], ",") {
+			if opt == "omitempty" {
+				omitempty = true
+			}
+		}
+	}
+	return name, omitempty
+}
+
+// isEmptyValue reports whether field holds its type's zero value, the same
+// notion of "empty" encoding/json uses for its own omitempty tag.
+func isEmptyValue(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return field.Len() == 0
+	case reflect.Bool:
+		return !field.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return field.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return field.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return field.IsNil()
+	}
+	return false
+}
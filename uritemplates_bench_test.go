@@ -0,0 +1,70 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"bytes"
+	"testing"
+)
+
+var benchValues = map[string]interface{}{
+	"user":  "jtacoma",
+	"repo":  "uritemplates",
+	"query": "path with spaces/and+reserved?chars",
+	"list":  []interface{}{"red", "green", "blue"},
+}
+
+func BenchmarkExpand(b *testing.B) {
+	template := MustParse("https://api.github.com/repos{/user,repo}{?query}{&list*}")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := template.Expand(benchValues); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExpandTo measures expanding into an existing *bytes.Buffer, which
+// ExpandTo recognizes and writes into directly instead of going through the
+// pooled buffer Expand uses.
+func BenchmarkExpandTo(b *testing.B) {
+	template := MustParse("https://api.github.com/repos{/user,repo}{?query}{&list*}")
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if _, err := template.ExpandTo(&buf, benchValues); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExpandToCompiled is identical to BenchmarkExpandTo except the
+// template is parsed with ParseCompiled, so the first ExpandTo call is not
+// the one that builds the expansion plan.
+func BenchmarkExpandToCompiled(b *testing.B) {
+	template, err := ParseCompiled("https://api.github.com/repos{/user,repo}{?query}{&list*}")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		if _, err := template.ExpandTo(&buf, benchValues); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEscapeTo(b *testing.B) {
+	var buf bytes.Buffer
+	s := "path with spaces/and+reserved?chars"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		escapeTo(&buf, s, false)
+	}
+}
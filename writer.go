@@ -0,0 +1,58 @@
+// Copyright 2013 Joshua Tacoma. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package uritemplates
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// bufferPool holds *bytes.Buffer values reused across Expand/ExpandTo calls
+// so that repeated expansion of the same (or different) templates does not
+// allocate a fresh buffer every time.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// ExpandTo expands a URI template with a set of values like Expand, but
+// writes the result directly to w instead of allocating and returning a
+// string, returning the number of bytes written. This avoids an allocation
+// when the destination is already a buffer, an *os.File, an http request
+// body, or similar.
+func (self *UriTemplate) ExpandTo(w io.Writer, value interface{}) (int, error) {
+	values, ismap := value.(map[string]interface{})
+	if !ismap {
+		if m, ismap := struct2map(value); !ismap {
+			return 0, errors.New("expected map[string]interface{}, struct, or pointer to struct.")
+		} else {
+			return self.ExpandTo(w, m)
+		}
+	}
+	plan := self.plan()
+	// If w is already a *bytes.Buffer, expand directly into it: the
+	// per-part expansion needs to rewind over what it just wrote (to drop
+	// an operator when none of its terms end up present), which only a
+	// buffer - not an arbitrary io.Writer - supports.
+	if buf, ok := w.(*bytes.Buffer); ok {
+		start := buf.Len()
+		for i, p := range self.Parts {
+			if err := p.expand(buf, values, &plan[i]); err != nil {
+				return 0, err
+			}
+		}
+		return buf.Len() - start, nil
+	}
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer bufferPool.Put(buf)
+	for i, p := range self.Parts {
+		if err := p.expand(buf, values, &plan[i]); err != nil {
+			return 0, err
+		}
+	}
+	return w.Write(buf.Bytes())
+}